@@ -2,23 +2,57 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
 	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 )
 
+// version is the build version, overridden at build time with
+// -ldflags "-X main.version=..."
+var version = "dev"
+
+// autosave makes every mutating command save the database once it succeeds
+var autosave bool
+
+// readOnly rejects every mutating command and disables saving
+var readOnly bool
+
+// noOverwrite makes new fail on an existing array; new! still overwrites
+var noOverwrite bool
+
+// confirmDestructive makes commands that discard data ask for
+// confirmation, or require --yes when there is nobody to ask
+var confirmDestructive bool
+
 // Database represents the structure of the database
 type Database struct {
 	filename string
 	data     map[string][]int
-	mutex    sync.Mutex
+	mutex    sync.RWMutex
+	compress bool
 }
 
 // NewDatabase initializes a new database
@@ -29,7 +63,9 @@ func NewDatabase(filename string) *Database {
 	}
 }
 
-// Initialize loads an existing database from a file
+// Initialize loads an existing database from a file. Both plain and
+// gzip-compressed files are accepted, and a compressed file stays
+// compressed when it is saved again.
 func (db *Database) Initialize() error {
 	file, err := os.Open(db.filename)
 	if err != nil {
@@ -37,31 +73,153 @@ func (db *Database) Initialize() error {
 	}
 	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	if err := decoder.Decode(&db.data); err != nil {
+	// Decode into a fresh map so that reloading discards keys that only
+	// exist in memory
+	data, compressed, err := decodeData(file)
+	if err != nil {
 		return err
 	}
 
+	// Keep a compressed file compressed even when -compress is not passed
+	db.mutex.Lock()
+	db.data = data
+	if compressed {
+		db.compress = true
+	}
+	db.mutex.Unlock()
 	return nil
 }
 
-// Save writes the database to a file
+// Save writes the database to a file. The data is written to a temporary
+// file first and renamed over the target so the original is never left
+// half-written.
 func (db *Database) Save() error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
-	file, err := os.Create(db.filename)
+	return db.writeFile(db.filename)
+}
+
+// Backup writes a copy of the database to path without touching the
+// primary file, using the same atomic rename as Save
+func (db *Database) Backup(path string) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.writeFile(path)
+}
+
+// writeFile atomically writes the database to path via a temporary file.
+// The caller must hold at least a read lock on the mutex.
+func (db *Database) writeFile(path string) error {
+	tmpName := path + ".tmp"
+	file, err := os.Create(tmpName)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	encoder := gob.NewEncoder(file)
-	if err := encoder.Encode(db.data); err != nil {
+	if err := db.encodeData(file); err != nil {
+		file.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
+
+// formatMagic starts every database file written in the versioned format,
+// followed by a uint32 format version and a CRC32 checksum of the payload
+const formatMagic = "WKNDB"
+
+// formatVersion is the version of the file format written by Save. Files
+// without the header are read as legacy raw gob data.
+const formatVersion uint32 = 1
+
+// encodeData writes the gob-encoded data, gzip-compressed if compression
+// is enabled, behind a header holding the format version and the payload
+// checksum. The caller must hold the mutex.
+func (db *Database) encodeData(w io.Writer) error {
+	var payload bytes.Buffer
+	if err := db.encodePayload(&payload); err != nil {
 		return err
 	}
 
-	return nil
+	header := make([]byte, len(formatMagic)+8)
+	copy(header, formatMagic)
+	binary.BigEndian.PutUint32(header[len(formatMagic):], formatVersion)
+	binary.BigEndian.PutUint32(header[len(formatMagic)+4:], crc32.ChecksumIEEE(payload.Bytes()))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload.Bytes())
+	return err
+}
+
+// encodePayload writes the gob-encoded data, gzip-compressed if
+// compression is enabled
+func (db *Database) encodePayload(w io.Writer) error {
+	if !db.compress {
+		return gob.NewEncoder(w).Encode(db.data)
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := gob.NewEncoder(gz).Encode(db.data); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// decodeData reads data written by encodeData, checking the format
+// version and verifying the checksum. Legacy headerless files are still
+// accepted. It also reports whether the payload was gzip-compressed.
+func decodeData(r io.Reader) (map[string][]int, bool, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !bytes.HasPrefix(raw, []byte(formatMagic)) {
+		return decodePayload(raw)
+	}
+
+	headerLen := len(formatMagic) + 8
+	if len(raw) < headerLen {
+		return nil, false, errors.New("database file is corrupted")
+	}
+	if binary.BigEndian.Uint32(raw[len(formatMagic):]) != formatVersion {
+		return nil, false, errors.New("unsupported database version")
+	}
+	payload := raw[headerLen:]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(raw[len(formatMagic)+4:]) {
+		return nil, false, errors.New("database file is corrupted")
+	}
+	return decodePayload(payload)
+}
+
+// decodePayload decodes gob data, transparently decompressing it when it
+// starts with the gzip magic bytes, and reports whether it did
+func decodePayload(payload []byte) (map[string][]int, bool, error) {
+	var src io.Reader = bytes.NewReader(payload)
+	compressed := len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b
+	if compressed {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, false, err
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	data := make(map[string][]int)
+	if err := gob.NewDecoder(src).Decode(&data); err != nil {
+		return nil, false, err
+	}
+	return data, compressed, nil
 }
 
 // Set inserts or updates a key-value pair in the database
@@ -72,17 +230,32 @@ func (db *Database) Set(key string, value []int) {
 	db.data[key] = value
 }
 
-// Get retrieves the value associated with a key from the database
-func (db *Database) Get(key string) ([]int, error) {
+// Create inserts a new key-value pair, failing if the key already exists
+func (db *Database) Create(key string, value []int) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
+	if _, ok := db.data[key]; ok {
+		return errors.New("key already exists")
+	}
+
+	db.data[key] = value
+	return nil
+}
+
+// Get retrieves a copy of the value associated with a key from the database
+func (db *Database) Get(key string) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
 	value, ok := db.data[key]
 	if !ok {
 		return nil, errors.New("key not found")
 	}
 
-	return value, nil
+	result := make([]int, len(value))
+	copy(result, value)
+	return result, nil
 }
 
 // Delete removes a key-value pair from the database
@@ -101,6 +274,9 @@ func (db *Database) Delete(key string) error {
 
 // Merge merges the content of two arrays
 func (db *Database) Merge(destKey, srcKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
 	dest, ok := db.data[destKey]
 	if !ok {
 		return errors.New("destination array does not exist")
@@ -110,23 +286,52 @@ func (db *Database) Merge(destKey, srcKey string) error {
 		return errors.New("source array does not exist")
 	}
 
-	db.data[destKey] = append(dest, src...)
+	// Build a fresh slice so that slices previously handed out for either
+	// array never observe the merge
+	merged := make([]int, len(dest)+len(src))
+	copy(merged, dest)
+	copy(merged[len(dest):], src)
+	db.data[destKey] = merged
 	return nil
 }
 
-// Show prints the content of an array
-func (db *Database) Show(key string) error {
+// Show prints the content of an array in the given output format
+func (db *Database) Show(w io.Writer, key, format string) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
 	value, ok := db.data[key]
 	if !ok {
 		return errors.New("array does not exist")
 	}
 
-	fmt.Println(value)
+	switch format {
+	case "json":
+		if value == nil {
+			value = []int{}
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+	case "csv":
+		fields := make([]string, len(value))
+		for i, v := range value {
+			fields[i] = strconv.Itoa(v)
+		}
+		fmt.Fprintln(w, strings.Join(fields, ","))
+	default:
+		fmt.Fprintln(w, value)
+	}
 	return nil
 }
 
 // Sort sorts the content of an array
 func (db *Database) Sort(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
 	value, ok := db.data[key]
 	if !ok {
 		return errors.New("array does not exist")
@@ -137,125 +342,3626 @@ func (db *Database) Sort(key string) error {
 	return nil
 }
 
-func main() {
-	var dbPath string
-	flag.StringVar(&dbPath, "db-path", ".wkn", "Path to the database file")
-	flag.Parse()
+// SortDesc sorts the content of an array in descending order
+func (db *Database) SortDesc(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
 
-	// Ensure the database file path is relative to the current directory
-	dbPath = filepath.Join(".", dbPath)
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("array does not exist")
+	}
 
-	db := NewDatabase(dbPath)
+	sort.Sort(sort.Reverse(sort.IntSlice(value)))
+	db.data[key] = value
+	return nil
+}
 
-	// Check if the database file exists
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		// Initialize a new database
-		err := db.Save()
-		if err != nil {
-			fmt.Println("Error creating database file:", err)
-			return
-		}
-	} else {
-		// Load existing database
-		err := db.Initialize()
-		if err != nil {
-			fmt.Println("Error loading database:", err)
-			return
-		}
+// GetIndex retrieves a single element of an array by index
+func (db *Database) GetIndex(key string, idx int) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
 	}
 
-	// Start the REPL
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		fmt.Print("wkn> ")
-		if !scanner.Scan() {
-			break
-		}
-		line := scanner.Text()
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
-			continue
-		}
+	i, ok := resolveIndex(idx, len(value))
+	if !ok {
+		return 0, errors.New("index out of range")
+	}
 
-		switch parts[0] {
-		case "new":
-			if len(parts) < 2 {
-				fmt.Println("Usage: new <array_name> [<comma-separated-values>]")
-				continue
-			}
-			key := parts[1]
-			var values []int
-			if len(parts) > 2 {
-				values = parseIntArray(parts[2])
-			}
-			db.Set(key, values)
-			fmt.Println("CREATED")
-		case "show":
-			if len(parts) != 2 {
-				fmt.Println("Usage: show <array_name>")
-				continue
-			}
-			key := parts[1]
-			err := db.Show(key)
-			if err != nil {
-				fmt.Println("Error:", err)
-			}
-		case "del":
-			if len(parts) != 2 {
-				fmt.Println("Usage: del <array_name>")
-				continue
-			}
-			key := parts[1]
-			err := db.Delete(key)
-			if err != nil {
-				fmt.Println("Error:", err)
-			} else {
-				fmt.Println("DELETED")
-			}
-		case "merge":
-			if len(parts) != 3 {
-				fmt.Println("Usage: merge <dest_array_name> <src_array_name>")
-				continue
-			}
-			destKey := parts[1]
-			srcKey := parts[2]
-			err := db.Merge(destKey, srcKey)
-			if err != nil {
-				fmt.Println("Error:", err)
-			} else {
-				fmt.Println("MERGED")
-			}
-		case "exit":
-			err := db.Save()
-			if err != nil {
-				fmt.Println("Error saving database:", err)
-			}
-			fmt.Println("Bye!")
-			return
-		case "help":
-			fmt.Println("Commands:")
-			fmt.Println("  new <array_name> [<comma-separated-values>]: Create a new array")
-			fmt.Println("  show <array_name>: Print the content of an array")
-			fmt.Println("  del <array_name>: Delete an array")
-			fmt.Println("  merge <dest_array_name> <src_array_name>: Merge two arrays")
-			fmt.Println("  exit: Exit the REPL")
-			fmt.Println("  help: Show this help message")
-		default:
-			fmt.Println("Unknown command:", parts[0])
+	return value[i], nil
+}
+
+// SetIndex overwrites a single element of an array by index
+func (db *Database) SetIndex(key string, idx, value int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	i, ok := resolveIndex(idx, len(arr))
+	if !ok {
+		return errors.New("index out of range")
+	}
+
+	arr[i] = value
+	return nil
+}
+
+// Append adds values to the end of an existing array
+func (db *Database) Append(key string, values []int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	db.data[key] = append(arr, values...)
+	return nil
+}
+
+// Prepend adds values to the front of an existing array
+func (db *Database) Prepend(key string, values []int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	result := make([]int, 0, len(values)+len(arr))
+	result = append(result, values...)
+	result = append(result, arr...)
+	db.data[key] = result
+	return nil
+}
+
+// Insert places a value at the given index, shifting later elements right
+func (db *Database) Insert(key string, idx, value int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+	if idx < 0 || idx > len(arr) {
+		return errors.New("index out of range")
+	}
+
+	result := make([]int, 0, len(arr)+1)
+	result = append(result, arr[:idx]...)
+	result = append(result, value)
+	result = append(result, arr[idx:]...)
+	db.data[key] = result
+	return nil
+}
+
+// RemoveIndex removes a single element of an array by index
+func (db *Database) RemoveIndex(key string, idx int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	i, ok := resolveIndex(idx, len(arr))
+	if !ok {
+		return errors.New("index out of range")
+	}
+
+	result := make([]int, 0, len(arr)-1)
+	result = append(result, arr[:i]...)
+	result = append(result, arr[i+1:]...)
+	db.data[key] = result
+	return nil
+}
+
+// RemoveValue removes every occurrence of a value from an array and
+// returns how many elements were removed
+func (db *Database) RemoveValue(key string, value int) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	result := make([]int, 0, len(arr))
+	for _, v := range arr {
+		if v != value {
+			result = append(result, v)
 		}
 	}
+
+	db.data[key] = result
+	return len(arr) - len(result), nil
 }
 
-func parseIntArray(s string) []int {
-	parts := strings.Split(s, ",")
-	var result []int
-	for _, part := range parts {
-		n, err := strconv.Atoi(part)
-		if err != nil {
-			fmt.Println("Error parsing value:", err)
-			return nil
+// Len returns the number of elements in an array
+func (db *Database) Len(key string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	return len(value), nil
+}
+
+// Keys returns the names of all arrays in alphabetical order
+func (db *Database) Keys() []string {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	keys := make([]string, 0, len(db.data))
+	for key := range db.data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// KeysMatching returns the names of the arrays matching a glob pattern, as
+// understood by filepath.Match, in alphabetical order
+func (db *Database) KeysMatching(pattern string) ([]string, error) {
+	// Matching against an empty name reports a malformed pattern even
+	// when the database is empty
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, errors.New("invalid pattern: " + pattern)
+	}
+
+	keys := []string{}
+	for _, key := range db.Keys() {
+		if ok, _ := filepath.Match(pattern, key); ok {
+			keys = append(keys, key)
 		}
-		result = append(result, n)
 	}
-	return result
+	return keys, nil
+}
+
+// Rename moves an array to a new key
+func (db *Database) Rename(oldKey, newKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[oldKey]
+	if !ok {
+		return errors.New("key not found")
+	}
+	if _, ok := db.data[newKey]; ok {
+		return errors.New("key already exists")
+	}
+
+	db.data[newKey] = value
+	delete(db.data, oldKey)
+	return nil
+}
+
+// Copy duplicates an array under a new key
+func (db *Database) Copy(srcKey, destKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	src, ok := db.data[srcKey]
+	if !ok {
+		return errors.New("source array does not exist")
+	}
+
+	dest := make([]int, len(src))
+	copy(dest, src)
+	db.data[destKey] = dest
+	return nil
+}
+
+// Clear removes all elements from an array while keeping its key
+func (db *Database) Clear(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, ok := db.data[key]; !ok {
+		return errors.New("key not found")
+	}
+
+	db.data[key] = []int{}
+	return nil
+}
+
+// Reverse reverses the order of the elements in an array
+func (db *Database) Reverse(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	for i, j := 0, len(value)-1; i < j; i, j = i+1, j-1 {
+		value[i], value[j] = value[j], value[i]
+	}
+	return nil
+}
+
+// Unique removes duplicate values from an array, keeping the first
+// occurrence of each, and returns how many elements were removed
+func (db *Database) Unique(key string) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	seen := make(map[int]bool, len(arr))
+	result := make([]int, 0, len(arr))
+	for _, v := range arr {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+
+	db.data[key] = result
+	return len(arr) - len(result), nil
+}
+
+// Sum returns the sum of all elements in an array. The total is
+// accumulated in an int64 and wraps around on overflow.
+func (db *Database) Sum(key string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	var total int64
+	for _, v := range value {
+		total += int64(v)
+	}
+	return int(total), nil
+}
+
+// Min returns the smallest element of an array
+func (db *Database) Min(key string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return 0, errors.New("empty array")
+	}
+
+	result := value[0]
+	for _, v := range value[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+// Max returns the largest element of an array
+func (db *Database) Max(key string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return 0, errors.New("empty array")
+	}
+
+	result := value[0]
+	for _, v := range value[1:] {
+		if v > result {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+// Avg returns the arithmetic mean of an array
+func (db *Database) Avg(key string) (float64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return 0, errors.New("empty array")
+	}
+
+	var total int64
+	for _, v := range value {
+		total += int64(v)
+	}
+	return float64(total) / float64(len(value)), nil
+}
+
+// Median returns the middle value of an array without reordering it
+func (db *Database) Median(key string) (float64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return 0, errors.New("empty array")
+	}
+
+	return median(value), nil
+}
+
+// Stats holds summary statistics for an array
+type Stats struct {
+	Count  int
+	Sum    int
+	Min    int
+	Max    int
+	Mean   float64
+	Median float64
+	StdDev float64
+}
+
+// Stats computes summary statistics for an array. An empty array yields
+// zeroed statistics rather than an error.
+func (db *Database) Stats(key string) (Stats, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return Stats{}, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return Stats{}, nil
+	}
+
+	s := Stats{
+		Count:  len(value),
+		Min:    value[0],
+		Max:    value[0],
+		Median: median(value),
+	}
+	var total int64
+	for _, v := range value {
+		total += int64(v)
+		if v < s.Min {
+			s.Min = v
+		}
+		if v > s.Max {
+			s.Max = v
+		}
+	}
+	s.Sum = int(total)
+	s.Mean = float64(total) / float64(s.Count)
+	s.StdDev = stdDev(value, s.Mean)
+	return s, nil
+}
+
+// Filter returns the elements of an array matching a predicate ("even" or
+// "odd") without modifying the stored array
+func (db *Database) Filter(key string, pred string) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+
+	var want int
+	switch pred {
+	case "even":
+		want = 0
+	case "odd":
+		want = 1
+	default:
+		return nil, errors.New("unknown predicate: " + pred)
+	}
+
+	result := []int{}
+	for _, v := range value {
+		if v%2 == want || v%2 == -want {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// Contains reports whether a value appears in an array
+func (db *Database) Contains(key string, value int) (bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return false, errors.New("key not found")
+	}
+
+	for _, v := range arr {
+		if v == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Count returns how many times a value appears in an array
+func (db *Database) Count(key string, value int) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	n := 0
+	for _, v := range arr {
+		if v == value {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ExportJSON writes the whole database as pretty-printed JSON. Keys are
+// sorted so the output is deterministic.
+func (db *Database) ExportJSON(w io.Writer) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	out := make(map[string][]int, len(db.data))
+	for key, value := range db.data {
+		if value == nil {
+			value = []int{}
+		}
+		out[key] = value
+	}
+
+	// encoding/json sorts map keys when marshaling
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	_, err = w.Write(data)
+	return err
+}
+
+// ImportJSON loads arrays from JSON produced by ExportJSON and returns how
+// many keys were imported. When replace is true the existing data is
+// discarded first; otherwise imported keys overwrite existing ones.
+func (db *Database) ImportJSON(r io.Reader, replace bool) (int, error) {
+	var in map[string][]int
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&in); err != nil {
+		return 0, errors.New("invalid database JSON: " + err.Error())
+	}
+	// null decodes into a nil map, which would silently wipe the database
+	// when replacing
+	if in == nil {
+		return 0, errors.New("invalid database JSON: expected an object of arrays")
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return 0, errors.New("invalid database JSON: unexpected data after object")
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if replace {
+		db.data = make(map[string][]int, len(in))
+	}
+	for key, value := range in {
+		if value == nil {
+			value = []int{}
+		}
+		db.data[key] = value
+	}
+	return len(in), nil
+}
+
+// ExportCSV writes an array as a single comma-separated CSV record so it
+// can be opened as one spreadsheet row
+func (db *Database) ExportCSV(key string, w io.Writer) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	record := make([]string, len(value))
+	for i, v := range value {
+		record[i] = strconv.Itoa(v)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Slice copies the elements src[start:end] into a new array. Negative
+// bounds count from the end of the source array.
+func (db *Database) Slice(srcKey, destKey string, start, end int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	src, ok := db.data[srcKey]
+	if !ok {
+		return errors.New("source array does not exist")
+	}
+
+	from, ok := resolveBound(start, len(src))
+	if !ok {
+		return errors.New("index out of range")
+	}
+	to, ok := resolveBound(end, len(src))
+	if !ok || from > to {
+		return errors.New("index out of range")
+	}
+
+	dest := make([]int, to-from)
+	copy(dest, src[from:to])
+	db.data[destKey] = dest
+	return nil
+}
+
+// Intersect stores the values present in both a and b into dest. The
+// result is sorted and contains each value once.
+func (db *Database) Intersect(destKey, aKey, bKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	a, b, err := db.lookupPair(aKey, bKey)
+	if err != nil {
+		return err
+	}
+
+	inB := make(map[int]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+	}
+	result := []int{}
+	for _, v := range a {
+		if inB[v] {
+			result = append(result, v)
+			delete(inB, v)
+		}
+	}
+
+	sort.Ints(result)
+	db.data[destKey] = result
+	return nil
+}
+
+// snapshot returns a deep copy of all arrays in the database
+func (db *Database) snapshot() map[string][]int {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	snap := make(map[string][]int, len(db.data))
+	for key, value := range db.data {
+		c := make([]int, len(value))
+		copy(c, value)
+		snap[key] = c
+	}
+	return snap
+}
+
+// restoreSnapshot replaces all arrays in the database with a snapshot
+func (db *Database) restoreSnapshot(snap map[string][]int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.data = snap
+}
+
+// lookupPair returns the arrays stored under two keys. The caller must
+// hold the mutex.
+func (db *Database) lookupPair(aKey, bKey string) ([]int, []int, error) {
+	a, ok := db.data[aKey]
+	if !ok {
+		return nil, nil, errors.New("source array does not exist: " + aKey)
+	}
+	b, ok := db.data[bKey]
+	if !ok {
+		return nil, nil, errors.New("source array does not exist: " + bKey)
+	}
+	return a, b, nil
+}
+
+// Union stores the sorted unique values found in either a or b into dest
+func (db *Database) Union(destKey, aKey, bKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	a, b, err := db.lookupPair(aKey, bKey)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool, len(a)+len(b))
+	result := []int{}
+	for _, arr := range [][]int{a, b} {
+		for _, v := range arr {
+			if !seen[v] {
+				seen[v] = true
+				result = append(result, v)
+			}
+		}
+	}
+
+	sort.Ints(result)
+	db.data[destKey] = result
+	return nil
+}
+
+// Difference stores the values of a that do not appear in b into dest,
+// keeping the order and duplicates of a
+func (db *Database) Difference(destKey, aKey, bKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	a, b, err := db.lookupPair(aKey, bKey)
+	if err != nil {
+		return err
+	}
+
+	inB := make(map[int]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+	}
+	result := []int{}
+	for _, v := range a {
+		if !inB[v] {
+			result = append(result, v)
+		}
+	}
+
+	db.data[destKey] = result
+	return nil
+}
+
+// MergeInto creates dest as the concatenation of a and b, leaving both
+// sources untouched
+func (db *Database) MergeInto(destKey, aKey, bKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, ok := db.data[destKey]; ok {
+		return errors.New("key already exists")
+	}
+	a, b, err := db.lookupPair(aKey, bKey)
+	if err != nil {
+		return err
+	}
+
+	merged := make([]int, len(a)+len(b))
+	copy(merged, a)
+	copy(merged[len(a):], b)
+	db.data[destKey] = merged
+	return nil
+}
+
+// Head returns a copy of the first n elements of an array, or the whole
+// array if it is shorter
+func (db *Database) Head(key string, n int) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	if n < 0 {
+		return nil, errors.New("count must not be negative")
+	}
+	if n > len(value) {
+		n = len(value)
+	}
+
+	result := make([]int, n)
+	copy(result, value[:n])
+	return result, nil
+}
+
+// Tail returns a copy of the last n elements of an array, or the whole
+// array if it is shorter
+func (db *Database) Tail(key string, n int) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	if n < 0 {
+		return nil, errors.New("count must not be negative")
+	}
+	if n > len(value) {
+		n = len(value)
+	}
+
+	result := make([]int, n)
+	copy(result, value[len(value)-n:])
+	return result, nil
+}
+
+// Rotate shifts the elements of an array left by n positions, wrapping
+// around. A negative n rotates right.
+func (db *Database) Rotate(key string, n int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return nil
+	}
+
+	k := ((n % len(value)) + len(value)) % len(value)
+	result := make([]int, 0, len(value))
+	result = append(result, value[k:]...)
+	result = append(result, value[:k]...)
+	db.data[key] = result
+	return nil
+}
+
+// Fill creates or overwrites an array with count copies of value
+func (db *Database) Fill(key string, value, count int) error {
+	if count < 0 {
+		return errors.New("count must not be negative")
+	}
+	if count > maxArrayLen {
+		return errors.New("count too large")
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	result := make([]int, count)
+	for i := range result {
+		result[i] = value
+	}
+	db.data[key] = result
+	return nil
+}
+
+// Range creates or overwrites an array with the arithmetic sequence from
+// start to end inclusive. Descending sequences need a negative step.
+func (db *Database) Range(key string, start, end, step int) error {
+	if step == 0 {
+		return errors.New("step must not be zero")
+	}
+	if (start < end && step < 0) || (start > end && step > 0) {
+		return errors.New("step moves away from end")
+	}
+
+	// Count the elements up front in unsigned arithmetic so sequences
+	// that end near the integer limits neither overflow nor loop forever
+	dist, stride := uint64(end)-uint64(start), uint64(step)
+	if step < 0 {
+		dist, stride = uint64(start)-uint64(end), -uint64(step)
+	}
+	if dist/stride >= maxArrayLen {
+		return errors.New("range too large")
+	}
+	count := dist/stride + 1
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	result := make([]int, count)
+	n := start
+	for i := range result {
+		result[i] = n
+		if i < len(result)-1 {
+			n += step
+		}
+	}
+	db.data[key] = result
+	return nil
+}
+
+// Chunk splits an array into consecutive arrays of at most size elements
+// named prefix_0, prefix_1, ... and returns the names it created
+func (db *Database) Chunk(srcKey, prefix string, size int) ([]string, error) {
+	if size <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	src, ok := db.data[srcKey]
+	if !ok {
+		return nil, errors.New("source array does not exist")
+	}
+
+	keys := []string{}
+	for i := 0; i < len(src); i += size {
+		end := i + size
+		if end > len(src) {
+			end = len(src)
+		}
+		chunk := make([]int, end-i)
+		copy(chunk, src[i:end])
+
+		key := prefix + "_" + strconv.Itoa(len(keys))
+		db.data[key] = chunk
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Truncate shortens an array to at most n elements
+func (db *Database) Truncate(key string, n int) error {
+	if n < 0 {
+		return errors.New("length must not be negative")
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+	if n >= len(value) {
+		return nil
+	}
+
+	result := make([]int, n)
+	copy(result, value[:n])
+	db.data[key] = result
+	return nil
+}
+
+// Swap exchanges the elements at indices i and j of an array
+func (db *Database) Swap(key string, i, j int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	a, ok := resolveIndex(i, len(value))
+	if !ok {
+		return errors.New("index out of range")
+	}
+	b, ok := resolveIndex(j, len(value))
+	if !ok {
+		return errors.New("index out of range")
+	}
+
+	value[a], value[b] = value[b], value[a]
+	return nil
+}
+
+// Replace substitutes every occurrence of oldValue in an array with
+// newValue and returns how many elements were replaced
+func (db *Database) Replace(key string, oldValue, newValue int) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	n := 0
+	for i, v := range value {
+		if v == oldValue {
+			value[i] = newValue
+			n++
+		}
+	}
+	return n, nil
+}
+
+// IndexOf returns the index of the first occurrence of a value in an
+// array, or -1 if it is absent
+func (db *Database) IndexOf(key string, value int) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	for i, v := range arr {
+		if v == value {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// LastIndexOf returns the index of the last occurrence of a value in an
+// array, or -1 if it is absent
+func (db *Database) LastIndexOf(key string, value int) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	arr, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	for i := len(arr) - 1; i >= 0; i-- {
+		if arr[i] == value {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// Scalar applies an arithmetic operation (add, sub, mul or div) with n to
+// every element of an array. Division truncates toward zero.
+func (db *Database) Scalar(key, op string, n int) error {
+	var apply func(int) int
+	switch op {
+	case "add":
+		apply = func(v int) int { return v + n }
+	case "sub":
+		apply = func(v int) int { return v - n }
+	case "mul":
+		apply = func(v int) int { return v * n }
+	case "div":
+		if n == 0 {
+			return errors.New("division by zero")
+		}
+		apply = func(v int) int { return v / n }
+	default:
+		return errors.New("unknown operator: " + op)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	for i, v := range value {
+		value[i] = apply(v)
+	}
+	return nil
+}
+
+// Abs replaces every element of an array with its absolute value. Since
+// math.MinInt has no positive counterpart it is clamped to math.MaxInt.
+func (db *Database) Abs(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	for i, v := range value {
+		switch {
+		case v == math.MinInt:
+			value[i] = math.MaxInt
+		case v < 0:
+			value[i] = -v
+		}
+	}
+	return nil
+}
+
+// CumSum returns the running totals of an array without modifying it
+func (db *Database) CumSum(key string) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+
+	result := make([]int, len(value))
+	total := 0
+	for i, v := range value {
+		total += v
+		result[i] = total
+	}
+	return result, nil
+}
+
+// Product returns the product of all elements in an array, or 1 for an
+// empty array. The product is accumulated in an int64 and wraps around on
+// overflow.
+func (db *Database) Product(key string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+
+	var total int64 = 1
+	for _, v := range value {
+		total *= int64(v)
+	}
+	return int(total), nil
+}
+
+// Shuffle randomly permutes an array. The same seed always produces the
+// same permutation.
+func (db *Database) Shuffle(key string, seed int64) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(value), func(i, j int) {
+		value[i], value[j] = value[j], value[i]
+	})
+	return nil
+}
+
+// Sample returns n distinct elements of an array chosen at random, or all
+// of them in random order if n exceeds the length. The same seed always
+// produces the same sample.
+func (db *Database) Sample(key string, n int, seed int64) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	if n < 0 {
+		return nil, errors.New("count must not be negative")
+	}
+	if n > len(value) {
+		n = len(value)
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	result := make([]int, n)
+	for i, j := range r.Perm(len(value))[:n] {
+		result[i] = value[j]
+	}
+	return result, nil
+}
+
+// IsSorted reports whether an array is in ascending order
+func (db *Database) IsSorted(key string) (bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return false, errors.New("key not found")
+	}
+
+	return sort.IntsAreSorted(value), nil
+}
+
+// BinarySearch looks up target in an array using sort.SearchInts and
+// returns the index where it is or would be inserted, and whether it was
+// found. The array must be sorted in ascending order (see IsSorted) for
+// the result to be meaningful.
+func (db *Database) BinarySearch(key string, target int) (int, bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, false, errors.New("key not found")
+	}
+
+	i := sort.SearchInts(value, target)
+	return i, i < len(value) && value[i] == target, nil
+}
+
+// TopK returns the k largest elements of an array in descending order
+// without modifying it. A min-heap of size k keeps this O(n log k).
+func (db *Database) TopK(key string, k int) ([]int, error) {
+	if k < 0 {
+		return nil, errors.New("k must not be negative")
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	if k > len(value) {
+		k = len(value)
+	}
+	if k == 0 {
+		return []int{}, nil
+	}
+
+	h := make(intHeap, 0, k)
+	for _, v := range value {
+		if len(h) < k {
+			heap.Push(&h, v)
+		} else if v > h[0] {
+			h[0] = v
+			heap.Fix(&h, 0)
+		}
+	}
+
+	result := []int(h)
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result, nil
+}
+
+// Freq returns how many times each distinct value appears in an array
+func (db *Database) Freq(key string) (map[int]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+
+	return frequencies(value), nil
+}
+
+// Mode returns the most frequent values of an array in ascending order.
+// More than one value is returned when several are tied.
+func (db *Database) Mode(key string) ([]int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return nil, errors.New("empty array")
+	}
+
+	counts := frequencies(value)
+	best := 0
+	for _, n := range counts {
+		if n > best {
+			best = n
+		}
+	}
+	result := []int{}
+	for v, n := range counts {
+		if n == best {
+			result = append(result, v)
+		}
+	}
+
+	sort.Ints(result)
+	return result, nil
+}
+
+// StdDev returns the population standard deviation of an array, i.e. the
+// variance is divided by the number of elements rather than n-1
+func (db *Database) StdDev(key string) (float64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	if len(value) == 0 {
+		return 0, errors.New("empty array")
+	}
+
+	var total int64
+	for _, v := range value {
+		total += int64(v)
+	}
+	return stdDev(value, float64(total)/float64(len(value))), nil
+}
+
+// MovingAvg returns the simple moving average of an array over a sliding
+// window, one value for each full window
+func (db *Database) MovingAvg(key string, window int) ([]float64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	if window <= 0 || window > len(value) {
+		return nil, errors.New("window must be between 1 and the array length")
+	}
+
+	result := make([]float64, 0, len(value)-window+1)
+	var total int64
+	for i, v := range value {
+		total += int64(v)
+		if i >= window {
+			total -= int64(value[i-window])
+		}
+		if i >= window-1 {
+			result = append(result, float64(total)/float64(window))
+		}
+	}
+	return result, nil
+}
+
+// Exists reports whether an array is stored under a key
+func (db *Database) Exists(key string) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	_, ok := db.data[key]
+	return ok
+}
+
+// Type reports the element type of an array. Only int arrays are
+// supported today.
+func (db *Database) Type(key string) (string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if _, ok := db.data[key]; !ok {
+		return "", errors.New("key not found")
+	}
+
+	return "int", nil
+}
+
+// Restore replaces the in-memory data with the contents of a backup file.
+// The primary file is left alone until the next save.
+func (db *Database) Restore(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, compressed, err := decodeData(file)
+	if err != nil {
+		return errors.New("not a valid database file: " + err.Error())
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.data = data
+	if compressed {
+		db.compress = true
+	}
+	return nil
+}
+
+// Flush removes every array from the database and returns how many were
+// removed
+func (db *Database) Flush() int {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	n := len(db.data)
+	db.data = make(map[string][]int)
+	return n
+}
+
+// DeleteMatching removes every array whose name matches a glob pattern and
+// returns how many were removed
+func (db *Database) DeleteMatching(pattern string) (int, error) {
+	if pattern == "" {
+		return 0, errors.New("pattern must not be empty")
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return 0, errors.New("invalid pattern: " + pattern)
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	n := 0
+	for key := range db.data {
+		if ok, _ := filepath.Match(pattern, key); ok {
+			delete(db.data, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// LoadFromFile creates a new array from a text file holding one integer
+// per line. Blank lines are skipped.
+func (db *Database) LoadFromFile(key, path string) error {
+	if db.Exists(key) {
+		return errors.New("key already exists")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	values := []int{}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		values = append(values, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, ok := db.data[key]; ok {
+		return errors.New("key already exists")
+	}
+	db.data[key] = values
+	return nil
+}
+
+// DumpToFile writes each element of an array on its own line, in the
+// format read by LoadFromFile. The file is written to a temporary name and
+// renamed into place so a partial file is never left behind.
+func (db *Database) DumpToFile(key, path string) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	tmpName := path + ".tmp"
+	file, err := os.Create(tmpName)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, v := range value {
+		fmt.Fprintln(writer, v)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
+
+// DBInfo summarizes the contents of the whole database
+type DBInfo struct {
+	Arrays     int
+	Elements   int
+	LargestKey string
+	LargestLen int
+	FileSize   int64
+}
+
+// Describe reports the number of arrays, the total number of elements, the
+// largest array and the size of the database file on disk. FileSize is -1
+// when the file cannot be read.
+func (db *Database) Describe() DBInfo {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	info := DBInfo{Arrays: len(db.data), FileSize: -1}
+	for key, value := range db.data {
+		info.Elements += len(value)
+		// Break ties alphabetically so the result is deterministic
+		if info.LargestKey == "" || len(value) > info.LargestLen ||
+			(len(value) == info.LargestLen && key < info.LargestKey) {
+			info.LargestKey = key
+			info.LargestLen = len(value)
+		}
+	}
+	if stat, err := os.Stat(db.filename); err == nil {
+		info.FileSize = stat.Size()
+	}
+	return info
+}
+
+// Add stores the element-wise sum of a and b, which must have the same
+// length, into dest as a new array
+func (db *Database) Add(destKey, aKey, bKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	a, b, err := db.lookupPair(aKey, bKey)
+	if err != nil {
+		return err
+	}
+	if len(a) != len(b) {
+		return errors.New("length mismatch")
+	}
+
+	result := make([]int, len(a))
+	for i := range a {
+		result[i] = a[i] + b[i]
+	}
+	db.data[destKey] = result
+	return nil
+}
+
+// Dot returns the dot product of two arrays of the same length
+func (db *Database) Dot(aKey, bKey string) (int, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	a, ok := db.data[aKey]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	b, ok := db.data[bKey]
+	if !ok {
+		return 0, errors.New("key not found")
+	}
+	if len(a) != len(b) {
+		return 0, errors.New("length mismatch")
+	}
+
+	total := 0
+	for i := range a {
+		total += a[i] * b[i]
+	}
+	return total, nil
+}
+
+// Zip interleaves the elements of a and b into dest as a[0], b[0], a[1],
+// b[1], ... stopping when the shorter array runs out
+func (db *Database) Zip(destKey, aKey, bKey string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	a, b, err := db.lookupPair(aKey, bKey)
+	if err != nil {
+		return err
+	}
+
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	result := make([]int, 0, 2*n)
+	for i := 0; i < n; i++ {
+		result = append(result, a[i], b[i])
+	}
+	db.data[destKey] = result
+	return nil
+}
+
+// Equal reports whether two arrays hold the same elements in the same order
+func (db *Database) Equal(aKey, bKey string) (bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	a, ok := db.data[aKey]
+	if !ok {
+		return false, errors.New("key not found")
+	}
+	b, ok := db.data[bKey]
+	if !ok {
+		return false, errors.New("key not found")
+	}
+	if len(a) != len(b) {
+		return false, nil
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Hash returns a hex-encoded SHA-256 fingerprint of an array. Each element
+// is encoded as a big-endian int64 so the hash is the same on every
+// platform.
+func (db *Database) Hash(key string) (string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	value, ok := db.data[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+
+	h := sha256.New()
+	buf := make([]byte, 8)
+	for _, v := range value {
+		binary.BigEndian.PutUint64(buf, uint64(int64(v)))
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func main() {
+	var dbPath string
+	var command string
+	var serveAddr string
+	var showVersion bool
+	var batch bool
+	var compress bool
+	var listenAddr string
+	var outputFormat string
+	flag.StringVar(&dbPath, "db-path", ".wkn", "Path to the database file (overrides $WKN_DB_PATH)")
+	flag.BoolVar(&autosave, "autosave", false, "Save the database after every mutating command")
+	flag.StringVar(&serveAddr, "serve", "", "Serve the database over HTTP on this address instead of starting the REPL")
+	flag.BoolVar(&showVersion, "version", false, "Print the version and exit")
+	flag.StringVar(&command, "c", "", "Run a single command and exit")
+	flag.BoolVar(&batch, "batch", false, "Read commands from stdin without a prompt (implied when stdin is not a terminal)")
+	flag.BoolVar(&compress, "compress", false, "Compress the database file with gzip when saving")
+	flag.StringVar(&listenAddr, "listen", "", "Serve the REPL commands over TCP on this address instead of starting the REPL")
+	flag.StringVar(&outputFormat, "format", "plain", "Output format for show: plain, json or csv")
+	flag.BoolVar(&readOnly, "readonly", false, "Reject every command that changes the database and never save it")
+	flag.BoolVar(&noOverwrite, "no-overwrite", false, "Make new fail instead of replacing an existing array (use new! to overwrite)")
+	flag.BoolVar(&confirmDestructive, "confirm-destructive", false, "Ask before del, flush!, restore! and overwriting new (batch and -c need --yes)")
+	flag.Parse()
+
+	if showVersion {
+		fmt.Println("wkn", version)
+		return
+	}
+
+	switch outputFormat {
+	case "plain", "json", "csv":
+	default:
+		fmt.Println("Unknown output format:", outputFormat)
+		return
+	}
+
+	// The database path is taken from -db-path if given, then from the
+	// WKN_DB_PATH environment variable, and finally defaults to .wkn
+	dbPathSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "db-path" {
+			dbPathSet = true
+		}
+	})
+	if envPath := os.Getenv("WKN_DB_PATH"); !dbPathSet && envPath != "" {
+		dbPath = envPath
+	}
+
+	// Resolve relative paths against the current directory; absolute
+	// paths such as a mounted volume are used as given
+	dbPath = filepath.Clean(dbPath)
+
+	db := NewDatabase(dbPath)
+	db.compress = compress
+
+	// Check if the database file exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		if readOnly {
+			fmt.Println("Error loading database:", err)
+			return
+		}
+		// Initialize a new database, creating its directory if needed
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+			fmt.Println("Error creating database directory:", err)
+			return
+		}
+		err := db.Save()
+		if err != nil {
+			fmt.Println("Error creating database file:", err)
+			return
+		}
+	} else {
+		// Load existing database
+		err := db.Initialize()
+		if err != nil {
+			fmt.Println("Error loading database:", err)
+			return
+		}
+	}
+
+	// Open the TCP listener up front so the shutdown handler can close it
+	var tcpServer *TCPServer
+	if listenAddr != "" {
+		var err error
+		tcpServer, err = ListenTCP(listenAddr, db)
+		if err != nil {
+			fmt.Println("Error listening:", err)
+			return
+		}
+		tcpServer.format = outputFormat
+	}
+
+	// Save before exiting on Ctrl-C or termination so unsaved changes
+	// are not lost
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		if tcpServer != nil {
+			tcpServer.Close()
+		}
+		if readOnly {
+			os.Exit(0)
+		}
+		fmt.Println()
+		fmt.Println("Saving...")
+		if err := db.Save(); err != nil {
+			fmt.Println("Error saving database:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}()
+
+	// Serve over HTTP instead of starting the REPL
+	if serveAddr != "" {
+		fmt.Println("Listening on", serveAddr)
+		if err := http.ListenAndServe(serveAddr, NewServer(db)); err != nil {
+			fmt.Println("Error serving database:", err)
+		}
+		return
+	}
+
+	// Serve the command language over TCP instead of starting the REPL
+	if tcpServer != nil {
+		fmt.Println("Listening on", tcpServer.Addr())
+		if err := tcpServer.Serve(); err != nil {
+			fmt.Println("Error serving database:", err)
+			return
+		}
+		// Serve only returns cleanly once the shutdown handler has closed
+		// the server, so wait for it to save and exit
+		select {}
+	}
+
+	// Run a single command and exit
+	if command != "" {
+		parts := splitCommand(command)
+		if len(parts) == 0 {
+			return
+		}
+		// Only rewrite the file when a command, possibly one run by
+		// source, actually changed the database
+		sess := newSession(db, os.Stdout)
+		sess.noUndo = true
+		sess.format = outputFormat
+		sess.dispatch(parts)
+		if sess.dirty && !readOnly {
+			if err := db.Save(); err != nil {
+				fmt.Println("Error saving database:", err)
+			}
+		}
+		return
+	}
+
+	// Commands piped in from another program are run without a prompt
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		batch = true
+	}
+
+	// Interactive sessions remember their commands between runs
+	var histPath string
+	if !batch {
+		histPath = historyPath()
+		history = loadHistory(histPath)
+	}
+
+	// Start the REPL
+	repl := newSession(db, os.Stdout)
+	repl.format = outputFormat
+	scanner := bufio.NewScanner(os.Stdin)
+	if !batch {
+		repl.in = scanner
+	} else {
+		repl.noUndo = true
+	}
+	for {
+		if !batch {
+			fmt.Print("wkn> ")
+		}
+		if !scanner.Scan() {
+			// Batch input has no exit command to rely on, so save at EOF
+			if batch && !readOnly {
+				if err := db.Save(); err != nil {
+					fmt.Println("Error saving database:", err)
+				}
+			}
+			break
+		}
+		line := scanner.Text()
+		parts := splitCommand(line)
+		if len(parts) == 0 {
+			continue
+		}
+		if !batch {
+			recordHistory(histPath, strings.TrimSpace(line))
+		}
+
+		if !repl.dispatch(parts) {
+			return
+		}
+	}
+}
+
+// maxHistory is the number of past commands loaded from the history file
+const maxHistory = 500
+
+// history holds the commands entered in interactive sessions, oldest first
+var history []string
+
+// historyPath returns the location of the history file, or an empty string
+// if the home directory cannot be determined
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".wkn_history")
+}
+
+// loadHistory reads the most recent commands from the history file
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+	}
+	return lines
+}
+
+// recordHistory adds a command to the in-memory history and appends it to
+// the history file straight away, so it survives an abrupt exit
+func recordHistory(path, line string) {
+	history = append(history, line)
+	if path == "" {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+// maxUndo is the number of snapshots kept for undo. Each snapshot is a
+// full copy of the database, so memory use grows with both this limit and
+// the size of the data.
+const maxUndo = 10
+
+// maxSourceDepth limits how deeply source commands may nest, which stops a
+// script that sources itself from recursing forever
+const maxSourceDepth = 16
+
+// session holds the state of one command session, such as the REPL or a
+// single TCP connection
+type session struct {
+	db  *Database
+	out io.Writer
+
+	// in reads answers to confirmation prompts, or is nil when the session
+	// is not interactive
+	in *bufio.Scanner
+
+	// undoStack holds snapshots taken before each mutation, most recent last
+	undoStack []map[string][]int
+
+	// noUndo disables undo and the snapshots behind it, for sessions that
+	// run a fixed set of commands and would only pay for the copies
+	noUndo bool
+
+	// sourceDepth is the number of source commands currently being run
+	sourceDepth int
+
+	// remote is set for TCP sessions, which may not touch the filesystem
+	// or undo changes that other clients may have built on
+	remote bool
+
+	// dirty is set once a command in the session has changed the database
+	dirty bool
+
+	// format selects how show prints arrays: plain, json or csv
+	format string
+}
+
+// newSession creates a session that runs commands against db and writes
+// their output to out in the plain format
+func newSession(db *Database, out io.Writer) *session {
+	return &session{db: db, out: out, format: "plain"}
+}
+
+// mutate runs a mutating operation and saves the database afterwards
+// when autosave is enabled. Unless undo is disabled, a snapshot is
+// recorded first so the change can be undone.
+func (s *session) mutate(op func() error) error {
+	if s.noUndo {
+		if err := op(); err != nil {
+			return err
+		}
+	} else {
+		snap := s.db.snapshot()
+		if err := op(); err != nil {
+			return err
+		}
+		s.undoStack = append(s.undoStack, snap)
+		if len(s.undoStack) > maxUndo {
+			s.undoStack = s.undoStack[1:]
+		}
+	}
+	s.dirty = true
+	if autosave {
+		if err := s.db.Save(); err != nil {
+			fmt.Fprintln(s.out, "Error saving database:", err)
+		}
+	}
+	return nil
+}
+
+// splitCommand splits an input line into its command and arguments.
+// Comment lines starting with # produce no parts, just like blank lines.
+func splitCommand(line string) []string {
+	parts := strings.Fields(line)
+	if len(parts) == 0 || strings.HasPrefix(parts[0], "#") {
+		return nil
+	}
+	return parts
+}
+
+// runScript executes each line of a file through dispatch. It returns
+// false when a command in the file ends the session.
+func (s *session) runScript(filename string) (bool, error) {
+	if s.sourceDepth >= maxSourceDepth {
+		return true, errors.New("source nesting too deep")
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return true, err
+	}
+	defer file.Close()
+
+	s.sourceDepth++
+	defer func() { s.sourceDepth-- }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := splitCommand(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		if !s.dispatch(parts) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
+
+// mutatingCommands lists the commands that change the database and are
+// therefore rejected in read-only mode
+var mutatingCommands = map[string]bool{
+	"abs":       true,
+	"append":    true,
+	"chunk":     true,
+	"clear":     true,
+	"copy":      true,
+	"cumsum":    true,
+	"del":       true,
+	"delmatch":  true,
+	"diff":      true,
+	"fill":      true,
+	"flush!":    true,
+	"import":    true,
+	"insert":    true,
+	"intersect": true,
+	"loadfile":  true,
+	"merge":     true,
+	"mergeinto": true,
+	"new":       true,
+	"new!":      true,
+	"prepend":   true,
+	"range":     true,
+	"remove":    true,
+	"removeval": true,
+	"rename":    true,
+	"replace":   true,
+	"restore!":  true,
+	"reverse":   true,
+	"rotate":    true,
+	"save":      true,
+	"scalar":    true,
+	"set":       true,
+	"shuffle":   true,
+	"slice":     true,
+	"sort":      true,
+	"sortdesc":  true,
+	"swap":      true,
+	"truncate":  true,
+	"undo":      true,
+	"union":     true,
+	"unique":    true,
+	"vadd":      true,
+	"zip":       true,
+}
+
+// localCommands lists the commands rejected in TCP sessions: those that
+// read or write files named by the client, and undo and reload!, which
+// replace the whole database and so would discard other clients' changes
+var localCommands = map[string]bool{
+	"backup":    true,
+	"csvexport": true,
+	"dumpfile":  true,
+	"export":    true,
+	"import":    true,
+	"loadfile":  true,
+	"reload!":   true,
+	"restore!":  true,
+	"source":    true,
+	"undo":      true,
+}
+
+// isMutating reports whether a command line would change the database
+func isMutating(parts []string) bool {
+	// cumsum only writes when given a destination array
+	if parts[0] == "cumsum" {
+		return len(parts) == 3
+	}
+	return mutatingCommands[parts[0]]
+}
+
+// isDestructive reports whether a command line would discard existing
+// data and so needs confirmation with -confirm-destructive
+func isDestructive(db *Database, parts []string) bool {
+	switch parts[0] {
+	case "del", "flush!", "restore!":
+		return true
+	case "new", "new!":
+		// new only overwrites when -no-overwrite is off
+		return len(parts) >= 2 && db.Exists(parts[1]) && (parts[0] == "new!" || !noOverwrite)
+	}
+	return false
+}
+
+// stripYes removes the --yes confirmation token from a command line and
+// reports whether it was present
+func stripYes(parts []string) ([]string, bool) {
+	result := make([]string, 0, len(parts))
+	yes := false
+	for _, part := range parts {
+		if part == "--yes" {
+			yes = true
+			continue
+		}
+		result = append(result, part)
+	}
+	return result, yes
+}
+
+// confirm asks the user to confirm a destructive command. Sessions without
+// interactive input cannot be asked, so they must pass --yes instead.
+func (s *session) confirm() bool {
+	if s.in == nil {
+		fmt.Fprintln(s.out, "Error: add --yes to confirm this command")
+		return false
+	}
+
+	fmt.Fprint(s.out, "Are you sure? (y/N) ")
+	if !s.in.Scan() {
+		fmt.Fprintln(s.out)
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(s.in.Text()))
+	if answer == "y" || answer == "yes" {
+		return true
+	}
+	fmt.Fprintln(s.out, "CANCELLED")
+	return false
+}
+
+// dispatch executes a single command against the database. It returns
+// false when the command ends the session.
+func (s *session) dispatch(parts []string) bool {
+	db := s.db
+	w := s.out
+
+	parts, yes := stripYes(parts)
+	if len(parts) == 0 {
+		return true
+	}
+	if s.remote && localCommands[parts[0]] {
+		fmt.Fprintln(w, "Error:", parts[0], "is not available over TCP")
+		return true
+	}
+	if readOnly && isMutating(parts) {
+		fmt.Fprintln(w, "Error: database is read-only")
+		return true
+	}
+	if confirmDestructive && !yes && isDestructive(db, parts) && !s.confirm() {
+		return true
+	}
+
+	switch parts[0] {
+	case "new", "new!":
+		if len(parts) < 2 {
+			fmt.Fprintln(w, "Usage:", parts[0], "<array_name> [<comma-separated-values>]")
+			return true
+		}
+		key := parts[1]
+		var values []int
+		if len(parts) > 2 {
+			var err error
+			values, err = parseIntArray(parts[2])
+			if err != nil {
+				fmt.Fprintln(w, "Error parsing value:", err)
+				return true
+			}
+		}
+		// With -no-overwrite only new! may replace an existing array
+		err := s.mutate(func() error {
+			if noOverwrite && parts[0] == "new" {
+				return db.Create(key, values)
+			}
+			db.Set(key, values)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "CREATED")
+		}
+	case "show":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: show <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := db.Show(w, key, s.format)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		}
+	case "del":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: del <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := s.mutate(func() error { return db.Delete(key) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "DELETED")
+		}
+	case "merge":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: merge <dest_array_name> <src_array_name>")
+			return true
+		}
+		destKey := parts[1]
+		srcKey := parts[2]
+		err := s.mutate(func() error { return db.Merge(destKey, srcKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "MERGED")
+		}
+	case "sort":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: sort <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := s.mutate(func() error { return db.Sort(key) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "SORTED")
+		}
+	case "sortdesc":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: sortdesc <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := s.mutate(func() error { return db.SortDesc(key) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "SORTED")
+		}
+	case "get":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: get <array_name> <index>")
+			return true
+		}
+		key := parts[1]
+		idx, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		value, err := db.GetIndex(key, idx)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, value)
+		}
+	case "set":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: set <array_name> <index> <value>")
+			return true
+		}
+		key := parts[1]
+		idx, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		value, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.SetIndex(key, idx, value) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "UPDATED")
+		}
+	case "append":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: append <array_name> <comma-separated-values>")
+			return true
+		}
+		key := parts[1]
+		values, err := parseIntArray(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error parsing value:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Append(key, values) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "APPENDED")
+		}
+	case "prepend":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: prepend <array_name> <comma-separated-values>")
+			return true
+		}
+		key := parts[1]
+		values, err := parseIntArray(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error parsing value:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Prepend(key, values) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "PREPENDED")
+		}
+	case "insert":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: insert <array_name> <index> <value>")
+			return true
+		}
+		key := parts[1]
+		idx, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		value, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Insert(key, idx, value) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "INSERTED")
+		}
+	case "remove":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: remove <array_name> <index>")
+			return true
+		}
+		key := parts[1]
+		idx, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.RemoveIndex(key, idx) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "REMOVED")
+		}
+	case "removeval":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: removeval <array_name> <value>")
+			return true
+		}
+		key := parts[1]
+		value, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		var removed int
+		err = s.mutate(func() error {
+			var err error
+			removed, err = db.RemoveValue(key, value)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "REMOVED", removed)
+		}
+	case "len":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: len <array_name>")
+			return true
+		}
+		key := parts[1]
+		n, err := db.Len(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, n)
+		}
+	case "keys":
+		if len(parts) > 2 {
+			fmt.Fprintln(w, "Usage: keys [<pattern>]")
+			return true
+		}
+		keys := db.Keys()
+		if len(parts) == 2 {
+			var err error
+			keys, err = db.KeysMatching(parts[1])
+			if err != nil {
+				fmt.Fprintln(w, "Error:", err)
+				return true
+			}
+		}
+		if len(keys) == 0 {
+			fmt.Fprintln(w, "(empty)")
+		}
+		for _, key := range keys {
+			fmt.Fprintln(w, key)
+		}
+	case "rename":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: rename <old_name> <new_name>")
+			return true
+		}
+		oldKey := parts[1]
+		newKey := parts[2]
+		err := s.mutate(func() error { return db.Rename(oldKey, newKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "RENAMED")
+		}
+	case "copy":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: copy <src_name> <dest_name>")
+			return true
+		}
+		srcKey := parts[1]
+		destKey := parts[2]
+		err := s.mutate(func() error { return db.Copy(srcKey, destKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "COPIED")
+		}
+	case "clear":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: clear <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := s.mutate(func() error { return db.Clear(key) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "CLEARED")
+		}
+	case "reverse":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: reverse <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := s.mutate(func() error { return db.Reverse(key) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "REVERSED")
+		}
+	case "unique":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: unique <array_name>")
+			return true
+		}
+		key := parts[1]
+		var removed int
+		err := s.mutate(func() error {
+			var err error
+			removed, err = db.Unique(key)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "REMOVED", removed, "DUPLICATES")
+		}
+	case "sum":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: sum <array_name>")
+			return true
+		}
+		key := parts[1]
+		total, err := db.Sum(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, total)
+		}
+	case "min":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: min <array_name>")
+			return true
+		}
+		key := parts[1]
+		value, err := db.Min(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, value)
+		}
+	case "max":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: max <array_name>")
+			return true
+		}
+		key := parts[1]
+		value, err := db.Max(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, value)
+		}
+	case "avg":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: avg <array_name>")
+			return true
+		}
+		key := parts[1]
+		mean, err := db.Avg(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, formatFloat(mean))
+		}
+	case "median":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: median <array_name>")
+			return true
+		}
+		key := parts[1]
+		value, err := db.Median(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, formatFloat(value))
+		}
+	case "stats":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: stats <array_name>")
+			return true
+		}
+		key := parts[1]
+		st, err := db.Stats(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "Count: ", st.Count)
+			fmt.Fprintln(w, "Sum:   ", st.Sum)
+			fmt.Fprintln(w, "Min:   ", st.Min)
+			fmt.Fprintln(w, "Max:   ", st.Max)
+			fmt.Fprintln(w, "Mean:  ", formatFloat(st.Mean))
+			fmt.Fprintln(w, "Median:", formatFloat(st.Median))
+			fmt.Fprintln(w, "StdDev:", formatFloat(st.StdDev))
+		}
+	case "filter":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: filter <array_name> even|odd")
+			return true
+		}
+		key := parts[1]
+		result, err := db.Filter(key, parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "contains":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: contains <array_name> <value>")
+			return true
+		}
+		key := parts[1]
+		value, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		found, err := db.Contains(key, value)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, found)
+		}
+	case "count":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: count <array_name> <value>")
+			return true
+		}
+		key := parts[1]
+		value, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		n, err := db.Count(key, value)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, n)
+		}
+	case "save":
+		if len(parts) != 1 {
+			fmt.Fprintln(w, "Usage: save")
+			return true
+		}
+		err := db.Save()
+		if err != nil {
+			fmt.Fprintln(w, "Error saving database:", err)
+		} else {
+			fmt.Fprintln(w, "SAVED")
+		}
+	case "export":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: export <filename>")
+			return true
+		}
+		file, err := os.Create(parts[1])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = db.ExportJSON(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "EXPORTED")
+		}
+	case "import":
+		if len(parts) != 2 && !(len(parts) == 3 && parts[2] == "replace") {
+			fmt.Fprintln(w, "Usage: import <filename> [replace]")
+			return true
+		}
+		replace := len(parts) == 3
+		file, err := os.Open(parts[1])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		var n int
+		err = s.mutate(func() error {
+			var err error
+			n, err = db.ImportJSON(file, replace)
+			return err
+		})
+		file.Close()
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "IMPORTED", n, "KEYS")
+		}
+	case "csvexport":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: csvexport <array_name> <filename>")
+			return true
+		}
+		key := parts[1]
+		if _, err := db.Len(key); err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		file, err := os.Create(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = db.ExportCSV(key, file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "EXPORTED")
+		}
+	case "source":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: source <filename>")
+			return true
+		}
+		keepGoing, err := s.runScript(parts[1])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		}
+		return keepGoing
+	case "slice":
+		if len(parts) != 5 {
+			fmt.Fprintln(w, "Usage: slice <src> <dest> <start> <end>")
+			return true
+		}
+		srcKey := parts[1]
+		destKey := parts[2]
+		start, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		end, err := strconv.Atoi(parts[4])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Slice(srcKey, destKey, start, end) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "SLICED")
+		}
+	case "intersect":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: intersect <dest> <a> <b>")
+			return true
+		}
+		destKey, aKey, bKey := parts[1], parts[2], parts[3]
+		err := s.mutate(func() error { return db.Intersect(destKey, aKey, bKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "INTERSECTED")
+		}
+	case "union":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: union <dest> <a> <b>")
+			return true
+		}
+		destKey, aKey, bKey := parts[1], parts[2], parts[3]
+		err := s.mutate(func() error { return db.Union(destKey, aKey, bKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "UNIONED")
+		}
+	case "diff":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: diff <dest> <a> <b>")
+			return true
+		}
+		destKey, aKey, bKey := parts[1], parts[2], parts[3]
+		err := s.mutate(func() error { return db.Difference(destKey, aKey, bKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "DIFFED")
+		}
+	case "mergeinto":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: mergeinto <new_dest> <a> <b>")
+			return true
+		}
+		destKey, aKey, bKey := parts[1], parts[2], parts[3]
+		err := s.mutate(func() error { return db.MergeInto(destKey, aKey, bKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "MERGED")
+		}
+	case "head":
+		if len(parts) != 2 && len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: head <array_name> [<n>]")
+			return true
+		}
+		key := parts[1]
+		n := 10
+		if len(parts) == 3 {
+			var err error
+			n, err = strconv.Atoi(parts[2])
+			if err != nil {
+				fmt.Fprintln(w, "Error:", err)
+				return true
+			}
+		}
+		result, err := db.Head(key, n)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "tail":
+		if len(parts) != 2 && len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: tail <array_name> [<n>]")
+			return true
+		}
+		key := parts[1]
+		n := 10
+		if len(parts) == 3 {
+			var err error
+			n, err = strconv.Atoi(parts[2])
+			if err != nil {
+				fmt.Fprintln(w, "Error:", err)
+				return true
+			}
+		}
+		result, err := db.Tail(key, n)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "rotate":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: rotate <array_name> <n>")
+			return true
+		}
+		key := parts[1]
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Rotate(key, n) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "ROTATED")
+		}
+	case "fill":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: fill <array_name> <value> <count>")
+			return true
+		}
+		key := parts[1]
+		value, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		count, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Fill(key, value, count) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "FILLED")
+		}
+	case "range":
+		if len(parts) != 4 && len(parts) != 5 {
+			fmt.Fprintln(w, "Usage: range <array_name> <start> <end> [step]")
+			return true
+		}
+		key := parts[1]
+		start, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		end, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		step := 1
+		if len(parts) == 5 {
+			step, err = strconv.Atoi(parts[4])
+			if err != nil {
+				fmt.Fprintln(w, "Error:", err)
+				return true
+			}
+		}
+		err = s.mutate(func() error { return db.Range(key, start, end, step) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "GENERATED")
+		}
+	case "chunk":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: chunk <src> <prefix> <size>")
+			return true
+		}
+		srcKey := parts[1]
+		prefix := parts[2]
+		size, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		var keys []string
+		err = s.mutate(func() error {
+			var err error
+			keys, err = db.Chunk(srcKey, prefix, size)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		}
+		for _, key := range keys {
+			fmt.Fprintln(w, key)
+		}
+	case "truncate":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: truncate <array_name> <n>")
+			return true
+		}
+		key := parts[1]
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Truncate(key, n) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "TRUNCATED")
+		}
+	case "swap":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: swap <array_name> <i> <j>")
+			return true
+		}
+		key := parts[1]
+		i, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		j, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Swap(key, i, j) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "SWAPPED")
+		}
+	case "replace":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: replace <array_name> <old> <new>")
+			return true
+		}
+		key := parts[1]
+		oldValue, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		newValue, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		var n int
+		err = s.mutate(func() error {
+			var err error
+			n, err = db.Replace(key, oldValue, newValue)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "REPLACED", n)
+		}
+	case "indexof", "lastindexof":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage:", parts[0], "<array_name> <value>")
+			return true
+		}
+		key := parts[1]
+		value, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		var idx int
+		if parts[0] == "indexof" {
+			idx, err = db.IndexOf(key, value)
+		} else {
+			idx, err = db.LastIndexOf(key, value)
+		}
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, idx)
+		}
+	case "scalar":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: scalar <array_name> add|sub|mul|div <n>")
+			return true
+		}
+		key := parts[1]
+		op := parts[2]
+		n, err := strconv.Atoi(parts[3])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		err = s.mutate(func() error { return db.Scalar(key, op, n) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "APPLIED")
+		}
+	case "abs":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: abs <array_name>")
+			return true
+		}
+		key := parts[1]
+		err := s.mutate(func() error { return db.Abs(key) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "APPLIED")
+		}
+	case "cumsum":
+		if len(parts) != 2 && len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: cumsum <array_name> [<dest_name>]")
+			return true
+		}
+		key := parts[1]
+		result, err := db.CumSum(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		if len(parts) == 3 {
+			destKey := parts[2]
+			s.mutate(func() error {
+				db.Set(destKey, result)
+				return nil
+			})
+			fmt.Fprintln(w, "CREATED")
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "product":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: product <array_name>")
+			return true
+		}
+		key := parts[1]
+		total, err := db.Product(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, total)
+		}
+	case "shuffle":
+		if len(parts) != 2 && len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: shuffle <array_name> [seed]")
+			return true
+		}
+		key := parts[1]
+		seed := time.Now().UnixNano()
+		if len(parts) == 3 {
+			var err error
+			seed, err = strconv.ParseInt(parts[2], 10, 64)
+			if err != nil {
+				fmt.Fprintln(w, "Error:", err)
+				return true
+			}
+		}
+		err := s.mutate(func() error { return db.Shuffle(key, seed) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "SHUFFLED")
+		}
+	case "sample":
+		if len(parts) != 3 && len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: sample <array_name> <n> [seed]")
+			return true
+		}
+		key := parts[1]
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		seed := time.Now().UnixNano()
+		if len(parts) == 4 {
+			seed, err = strconv.ParseInt(parts[3], 10, 64)
+			if err != nil {
+				fmt.Fprintln(w, "Error:", err)
+				return true
+			}
+		}
+		result, err := db.Sample(key, n, seed)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "issorted":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: issorted <array_name>")
+			return true
+		}
+		key := parts[1]
+		sorted, err := db.IsSorted(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, sorted)
+		}
+	case "bsearch":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: bsearch <array_name> <target>")
+			return true
+		}
+		key := parts[1]
+		target, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		idx, found, err := db.BinarySearch(key, target)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else if found {
+			fmt.Fprintln(w, idx)
+		} else {
+			fmt.Fprintf(w, "NOT FOUND (insert at %d)\n", idx)
+		}
+	case "topk":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: topk <array_name> <k>")
+			return true
+		}
+		key := parts[1]
+		k, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		result, err := db.TopK(key, k)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "freq":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: freq <array_name>")
+			return true
+		}
+		key := parts[1]
+		counts, err := db.Freq(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		values := make([]int, 0, len(counts))
+		for v := range counts {
+			values = append(values, v)
+		}
+		sort.Ints(values)
+		for _, v := range values {
+			fmt.Fprintf(w, "%d: %d\n", v, counts[v])
+		}
+	case "mode":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: mode <array_name>")
+			return true
+		}
+		key := parts[1]
+		result, err := db.Mode(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "stddev":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: stddev <array_name>")
+			return true
+		}
+		key := parts[1]
+		sd, err := db.StdDev(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, formatFloat(sd))
+		}
+	case "movavg":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: movavg <array_name> <window>")
+			return true
+		}
+		key := parts[1]
+		window, err := strconv.Atoi(parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+			return true
+		}
+		result, err := db.MovingAvg(key, window)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, result)
+		}
+	case "exists":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: exists <array_name>")
+			return true
+		}
+		fmt.Fprintln(w, db.Exists(parts[1]))
+	case "reload":
+		fmt.Fprintln(w, "This discards all unsaved changes. Use reload! to confirm.")
+	case "reload!":
+		if len(parts) != 1 {
+			fmt.Fprintln(w, "Usage: reload!")
+			return true
+		}
+		err := db.Initialize()
+		if err != nil {
+			fmt.Fprintln(w, "Error loading database:", err)
+		} else {
+			s.undoStack = nil
+			fmt.Fprintln(w, "RELOADED")
+		}
+	case "undo":
+		if len(parts) != 1 {
+			fmt.Fprintln(w, "Usage: undo")
+			return true
+		}
+		if s.noUndo {
+			fmt.Fprintln(w, "Error: undo is only available in the interactive REPL")
+			return true
+		}
+		if len(s.undoStack) == 0 {
+			fmt.Fprintln(w, "Error: nothing to undo")
+			return true
+		}
+		db.restoreSnapshot(s.undoStack[len(s.undoStack)-1])
+		s.undoStack = s.undoStack[:len(s.undoStack)-1]
+		s.dirty = true
+		if autosave {
+			if err := db.Save(); err != nil {
+				fmt.Fprintln(w, "Error saving database:", err)
+			}
+		}
+		fmt.Fprintln(w, "UNDONE")
+	case "history":
+		if len(parts) != 1 {
+			fmt.Fprintln(w, "Usage: history")
+			return true
+		}
+		for i, line := range history {
+			fmt.Fprintf(w, "%4d  %s\n", i+1, line)
+		}
+	case "type":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: type <array_name>")
+			return true
+		}
+		key := parts[1]
+		t, err := db.Type(key)
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, t)
+		}
+	case "backup":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: backup <path>")
+			return true
+		}
+		err := db.Backup(parts[1])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "BACKED UP")
+		}
+	case "restore":
+		fmt.Fprintln(w, "This replaces all data in memory. Use restore! <path> to confirm.")
+	case "restore!":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: restore! <path>")
+			return true
+		}
+		path := parts[1]
+		err := s.mutate(func() error { return db.Restore(path) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "RESTORED")
+		}
+	case "flush":
+		fmt.Fprintln(w, "This deletes every array. Use flush! to confirm.")
+	case "flush!":
+		if len(parts) != 1 {
+			fmt.Fprintln(w, "Usage: flush!")
+			return true
+		}
+		var n int
+		s.mutate(func() error {
+			n = db.Flush()
+			return nil
+		})
+		fmt.Fprintln(w, "FLUSHED", n, "KEYS")
+	case "delmatch":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: delmatch <pattern>")
+			return true
+		}
+		pattern := parts[1]
+		var n int
+		err := s.mutate(func() error {
+			var err error
+			n, err = db.DeleteMatching(pattern)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "DELETED", n)
+		}
+	case "loadfile":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: loadfile <array_name> <filename>")
+			return true
+		}
+		key := parts[1]
+		path := parts[2]
+		err := s.mutate(func() error { return db.LoadFromFile(key, path) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "LOADED")
+		}
+	case "dumpfile":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: dumpfile <array_name> <filename>")
+			return true
+		}
+		err := db.DumpToFile(parts[1], parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "DUMPED")
+		}
+	case "describe":
+		if len(parts) != 1 {
+			fmt.Fprintln(w, "Usage: describe")
+			return true
+		}
+		info := db.Describe()
+		fmt.Fprintf(w, "Arrays:    %d\n", info.Arrays)
+		fmt.Fprintf(w, "Elements:  %d\n", info.Elements)
+		if info.LargestKey != "" {
+			fmt.Fprintf(w, "Largest:   %s (%d elements)\n", info.LargestKey, info.LargestLen)
+		}
+		if info.FileSize >= 0 {
+			fmt.Fprintf(w, "File size: %d bytes\n", info.FileSize)
+		} else {
+			fmt.Fprintln(w, "File size: unknown")
+		}
+	case "vadd":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: vadd <dest> <a> <b>")
+			return true
+		}
+		destKey, aKey, bKey := parts[1], parts[2], parts[3]
+		err := s.mutate(func() error { return db.Add(destKey, aKey, bKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "ADDED")
+		}
+	case "dot":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: dot <a> <b>")
+			return true
+		}
+		total, err := db.Dot(parts[1], parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, total)
+		}
+	case "zip":
+		if len(parts) != 4 {
+			fmt.Fprintln(w, "Usage: zip <dest> <a> <b>")
+			return true
+		}
+		destKey, aKey, bKey := parts[1], parts[2], parts[3]
+		err := s.mutate(func() error { return db.Zip(destKey, aKey, bKey) })
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, "ZIPPED")
+		}
+	case "compare":
+		if len(parts) != 3 {
+			fmt.Fprintln(w, "Usage: compare <a> <b>")
+			return true
+		}
+		equal, err := db.Equal(parts[1], parts[2])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, equal)
+		}
+	case "hash":
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "Usage: hash <array_name>")
+			return true
+		}
+		sum, err := db.Hash(parts[1])
+		if err != nil {
+			fmt.Fprintln(w, "Error:", err)
+		} else {
+			fmt.Fprintln(w, sum)
+		}
+	case "exit":
+		if !readOnly {
+			err := db.Save()
+			if err != nil {
+				fmt.Fprintln(w, "Error saving database:", err)
+			}
+		}
+		fmt.Fprintln(w, "Bye!")
+		return false
+	case "help":
+		fmt.Fprintln(w, "Commands:")
+		fmt.Fprintln(w, "  new <array_name> [<comma-separated-values>]: Create a new array")
+		fmt.Fprintln(w, "  new! <array_name> [<comma-separated-values>]: Create or overwrite an array, even with -no-overwrite")
+		fmt.Fprintln(w, "  show <array_name>: Print the content of an array")
+		fmt.Fprintln(w, "  del <array_name>: Delete an array")
+		fmt.Fprintln(w, "  merge <dest_array_name> <src_array_name>: Merge two arrays")
+		fmt.Fprintln(w, "  sort <array_name>: Sort an array in ascending order")
+		fmt.Fprintln(w, "  sortdesc <array_name>: Sort an array in descending order")
+		fmt.Fprintln(w, "  get <array_name> <index>: Print a single element of an array")
+		fmt.Fprintln(w, "  set <array_name> <index> <value>: Update a single element of an array")
+		fmt.Fprintln(w, "  append <array_name> <comma-separated-values>: Append values to an array")
+		fmt.Fprintln(w, "  prepend <array_name> <comma-separated-values>: Insert values at the front of an array")
+		fmt.Fprintln(w, "  insert <array_name> <index> <value>: Insert a value at an index")
+		fmt.Fprintln(w, "  remove <array_name> <index>: Remove the element at an index")
+		fmt.Fprintln(w, "  removeval <array_name> <value>: Remove every occurrence of a value")
+		fmt.Fprintln(w, "  len <array_name>: Print the number of elements in an array")
+		fmt.Fprintln(w, "  keys [<pattern>]: List the names of all arrays, or those matching a glob pattern")
+		fmt.Fprintln(w, "  rename <old_name> <new_name>: Rename an array")
+		fmt.Fprintln(w, "  copy <src_name> <dest_name>: Copy an array to a new name")
+		fmt.Fprintln(w, "  clear <array_name>: Remove all elements from an array")
+		fmt.Fprintln(w, "  reverse <array_name>: Reverse the order of an array")
+		fmt.Fprintln(w, "  unique <array_name>: Remove duplicate values from an array")
+		fmt.Fprintln(w, "  sum <array_name>: Print the sum of an array")
+		fmt.Fprintln(w, "  min <array_name>: Print the smallest element of an array")
+		fmt.Fprintln(w, "  max <array_name>: Print the largest element of an array")
+		fmt.Fprintln(w, "  avg <array_name>: Print the mean of an array")
+		fmt.Fprintln(w, "  median <array_name>: Print the median of an array")
+		fmt.Fprintln(w, "  stats <array_name>: Print summary statistics for an array")
+		fmt.Fprintln(w, "  filter <array_name> even|odd: Print the even or odd elements of an array")
+		fmt.Fprintln(w, "  contains <array_name> <value>: Check whether a value is in an array")
+		fmt.Fprintln(w, "  count <array_name> <value>: Count the occurrences of a value in an array")
+		fmt.Fprintln(w, "  save: Write the database to disk")
+		fmt.Fprintln(w, "  export <filename>: Write the database to a JSON file")
+		fmt.Fprintln(w, "  import <filename> [replace]: Load arrays from a JSON file, optionally replacing all data")
+		fmt.Fprintln(w, "  csvexport <array_name> <filename>: Write an array to a CSV file as a single row")
+		fmt.Fprintln(w, "  source <filename>: Run the commands in a file")
+		fmt.Fprintln(w, "  slice <src> <dest> <start> <end>: Copy a range of an array into a new array")
+		fmt.Fprintln(w, "  intersect <dest> <a> <b>: Store the sorted unique values found in both arrays")
+		fmt.Fprintln(w, "  union <dest> <a> <b>: Store the sorted unique values found in either array")
+		fmt.Fprintln(w, "  diff <dest> <a> <b>: Store the values of a that are not in b")
+		fmt.Fprintln(w, "  mergeinto <new_dest> <a> <b>: Merge two arrays into a new array")
+		fmt.Fprintln(w, "  head <array_name> [<n>]: Print the first n elements of an array (default 10)")
+		fmt.Fprintln(w, "  tail <array_name> [<n>]: Print the last n elements of an array (default 10)")
+		fmt.Fprintln(w, "  rotate <array_name> <n>: Rotate an array left by n positions (right if negative)")
+		fmt.Fprintln(w, "  fill <array_name> <value> <count>: Create an array of count copies of value")
+		fmt.Fprintln(w, "  range <array_name> <start> <end> [step]: Create an array from start to end inclusive")
+		fmt.Fprintln(w, "  chunk <src> <prefix> <size>: Split an array into arrays named prefix_0, prefix_1, ...")
+		fmt.Fprintln(w, "  truncate <array_name> <n>: Shorten an array to at most n elements")
+		fmt.Fprintln(w, "  swap <array_name> <i> <j>: Exchange two elements of an array")
+		fmt.Fprintln(w, "  replace <array_name> <old> <new>: Replace every occurrence of a value")
+		fmt.Fprintln(w, "  indexof <array_name> <value>: Print the index of the first occurrence of a value")
+		fmt.Fprintln(w, "  lastindexof <array_name> <value>: Print the index of the last occurrence of a value")
+		fmt.Fprintln(w, "  scalar <array_name> add|sub|mul|div <n>: Apply arithmetic to every element of an array")
+		fmt.Fprintln(w, "  abs <array_name>: Replace every element of an array with its absolute value")
+		fmt.Fprintln(w, "  cumsum <array_name> [<dest_name>]: Print the running totals of an array, or store them in dest")
+		fmt.Fprintln(w, "  product <array_name>: Print the product of an array")
+		fmt.Fprintln(w, "  shuffle <array_name> [seed]: Randomly reorder an array, reproducibly when seeded")
+		fmt.Fprintln(w, "  sample <array_name> <n> [seed]: Print n randomly chosen elements of an array")
+		fmt.Fprintln(w, "  issorted <array_name>: Check whether an array is in ascending order")
+		fmt.Fprintln(w, "  bsearch <array_name> <target>: Binary search a sorted array for a value")
+		fmt.Fprintln(w, "  topk <array_name> <k>: Print the k largest elements of an array")
+		fmt.Fprintln(w, "  freq <array_name>: Print how often each value appears in an array")
+		fmt.Fprintln(w, "  mode <array_name>: Print the most frequent values of an array")
+		fmt.Fprintln(w, "  stddev <array_name>: Print the population standard deviation of an array")
+		fmt.Fprintln(w, "  movavg <array_name> <window>: Print the moving average of an array")
+		fmt.Fprintln(w, "  exists <array_name>: Check whether an array exists")
+		fmt.Fprintln(w, "  reload!: Discard unsaved changes and reload the database from disk")
+		fmt.Fprintln(w, "  undo: Revert the most recent change (up to 10 levels, REPL only;")
+		fmt.Fprintln(w, "        each level keeps a full copy of the database in memory)")
+		fmt.Fprintln(w, "  history: Print previously entered commands")
+		fmt.Fprintln(w, "  type <array_name>: Print the element type of an array")
+		fmt.Fprintln(w, "  backup <path>: Write a copy of the database to another file")
+		fmt.Fprintln(w, "  restore! <path>: Replace the data in memory with a backup file")
+		fmt.Fprintln(w, "  flush!: Delete every array in the database")
+		fmt.Fprintln(w, "  delmatch <pattern>: Delete every array whose name matches a glob pattern")
+		fmt.Fprintln(w, "  loadfile <array_name> <filename>: Create an array from a file with one integer per line")
+		fmt.Fprintln(w, "  dumpfile <array_name> <filename>: Write an array to a file with one value per line")
+		fmt.Fprintln(w, "  describe: Print a summary of the whole database")
+		fmt.Fprintln(w, "  vadd <dest> <a> <b>: Store the element-wise sum of two arrays")
+		fmt.Fprintln(w, "  dot <a> <b>: Print the dot product of two arrays")
+		fmt.Fprintln(w, "  zip <dest> <a> <b>: Interleave two arrays, stopping at the shorter one")
+		fmt.Fprintln(w, "  compare <a> <b>: Check whether two arrays are identical")
+		fmt.Fprintln(w, "  hash <array_name>: Print a SHA-256 fingerprint of an array")
+		fmt.Fprintln(w, "  exit: Exit the REPL")
+		fmt.Fprintln(w, "  help: Show this help message")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Value lists may contain ranges, e.g. 1-3,7,10-8.")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Run with -autosave to save after every mutating command.")
+		fmt.Fprintln(w, "This protects against lost changes but rewrites the whole")
+		fmt.Fprintln(w, "database file on each change, which is slow for large databases.")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Over -listen, undo, reload! and the commands that read or write")
+		fmt.Fprintln(w, "files (source, import, export, csvexport, loadfile, dumpfile,")
+		fmt.Fprintln(w, "backup, restore!) are not available.")
+	default:
+		fmt.Fprintln(w, "Unknown command:", parts[0])
+	}
+	return true
+}
+
+// parseIntArray parses a comma-separated list of integers and inclusive
+// ranges such as 1-3,7,10-8. Whitespace around each value is ignored, but
+// since the REPL splits its input on spaces the whole list must still be
+// passed as a single argument.
+func parseIntArray(s string) ([]int, error) {
+	parts := strings.Split(s, ",")
+	var result []int
+	for _, part := range parts {
+		values, err := parseIntToken(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, values...)
+	}
+	return result, nil
+}
+
+// maxArrayLen is the largest array a single command may build, so that
+// a typo in a count or range fails instead of exhausting memory
+const maxArrayLen = 10000000
+
+// parseIntToken parses a single integer or an inclusive range written as
+// <start>-<end>. Ranges count down when start is greater than end.
+func parseIntToken(tok string) ([]int, error) {
+	// Skip the first character so a leading minus sign is not mistaken
+	// for a range separator
+	sep := -1
+	if len(tok) > 1 {
+		sep = strings.Index(tok[1:], "-")
+	}
+	if sep < 0 {
+		n, err := strconv.Atoi(tok)
+		if err != nil {
+			return nil, err
+		}
+		return []int{n}, nil
+	}
+	sep++
+
+	start, err := strconv.Atoi(tok[:sep])
+	if err != nil {
+		return nil, errors.New("malformed range: " + tok)
+	}
+	end, err := strconv.Atoi(tok[sep+1:])
+	if err != nil {
+		return nil, errors.New("malformed range: " + tok)
+	}
+
+	step, dist := 1, uint64(end)-uint64(start)
+	if start > end {
+		step, dist = -1, uint64(start)-uint64(end)
+	}
+	if dist >= maxArrayLen {
+		return nil, errors.New("range too large: " + tok)
+	}
+	result := make([]int, 0, dist+1)
+	for n := start; ; n += step {
+		result = append(result, n)
+		if n == end {
+			break
+		}
+	}
+	return result, nil
+}
+
+// formatFloat formats a computed statistic with at most four decimal
+// places, dropping trailing zeros so whole numbers print without a fraction
+func formatFloat(v float64) string {
+	text := strconv.FormatFloat(v, 'f', 4, 64)
+	text = strings.TrimSuffix(strings.TrimRight(text, "0"), ".")
+	if text == "-0" {
+		return "0"
+	}
+	return text
+}
+
+// resolveIndex converts a possibly negative index into a position within
+// a slice of the given length, counting negative indices from the end
+func resolveIndex(idx, length int) (int, bool) {
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return 0, false
+	}
+	return idx, true
+}
+
+// median returns the middle value of a non-empty slice, averaging the two
+// middle values for even lengths. The input slice is left untouched.
+func median(values []int) float64 {
+	sorted := make([]int, len(values))
+	copy(sorted, values)
+	sort.Ints(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (float64(sorted[mid-1]) + float64(sorted[mid])) / 2
+	}
+	return float64(sorted[mid])
+}
+
+// stdDev returns the population standard deviation of a non-empty slice
+// around the given mean
+func stdDev(values []int, mean float64) float64 {
+	var sq float64
+	for _, v := range values {
+		d := float64(v) - mean
+		sq += d * d
+	}
+	return math.Sqrt(sq / float64(len(values)))
+}
+
+// resolveBound is like resolveIndex but also accepts the length itself,
+// for use as a slice bound
+func resolveBound(idx, length int) (int, bool) {
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx > length {
+		return 0, false
+	}
+	return idx, true
+}
+
+// intHeap is a min-heap of ints for use with container/heap
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *intHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// frequencies counts the occurrences of each distinct value in a slice
+func frequencies(values []int) map[int]int {
+	counts := make(map[int]int)
+	for _, v := range values {
+		counts[v]++
+	}
+	return counts
 }
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSetAndMerge(t *testing.T) {
+	db := NewDatabase("")
+	db.Set("a", []int{1})
+	db.Set("b", []int{2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			db.Set("b", []int{i})
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := db.Merge("a", "b"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := db.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value) != 51 {
+		t.Errorf("len(a) = %d, want 51", len(value))
+	}
+}
+
+func TestGetResultUnchangedByMerge(t *testing.T) {
+	db := NewDatabase("")
+	db.Set("a", []int{1, 2})
+	db.Set("b", []int{3})
+
+	held, err := db.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Merge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalInts(held, []int{1, 2}) {
+		t.Errorf("held = %v, want [1 2]", held)
+	}
+}
+
+// equalInts reports whether a and b hold the same elements in order
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMutatingGetResultLeavesStoreUnchanged(t *testing.T) {
+	db := NewDatabase("")
+	db.Set("a", []int{1, 2, 3})
+
+	value, err := db.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value[0] = 99
+
+	stored, err := db.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(stored, []int{1, 2, 3}) {
+		t.Errorf("stored = %v, want [1 2 3]", stored)
+	}
+}
+
+func TestParseIntToken(t *testing.T) {
+	tests := []struct {
+		tok  string
+		want []int
+	}{
+		{"3", []int{3}},
+		{"-3", []int{-3}},
+		{"1-4", []int{1, 2, 3, 4}},
+		{"4-1", []int{4, 3, 2, 1}},
+		{"-2-1", []int{-2, -1, 0, 1}},
+		{"5-5", []int{5}},
+	}
+	for _, tt := range tests {
+		got, err := parseIntToken(tt.tok)
+		if err != nil {
+			t.Errorf("parseIntToken(%q): %v", tt.tok, err)
+			continue
+		}
+		if !equalInts(got, tt.want) {
+			t.Errorf("parseIntToken(%q) = %v, want %v", tt.tok, got, tt.want)
+		}
+	}
+}
+
+func TestParseIntTokenErrors(t *testing.T) {
+	for _, tok := range []string{"1-", "a-3", "x", "0-9223372036854775806", "9223372036854775807--9223372036854775808"} {
+		if _, err := parseIntToken(tok); err == nil {
+			t.Errorf("parseIntToken(%q) succeeded, want error", tok)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	db := NewDatabase("")
+	if err := db.Fill("f", 7, 3); err != nil {
+		t.Fatal(err)
+	}
+	value, _ := db.Get("f")
+	if !equalInts(value, []int{7, 7, 7}) {
+		t.Errorf("f = %v, want [7 7 7]", value)
+	}
+
+	for _, count := range []int{-1, maxArrayLen + 1, 9223372036854775807} {
+		if err := db.Fill("f", 1, count); err == nil {
+			t.Errorf("Fill with count %d succeeded, want error", count)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		start, end, step int
+		want             []int
+	}{
+		{1, 5, 2, []int{1, 3, 5}},
+		{1, 6, 2, []int{1, 3, 5}},
+		{5, 1, -2, []int{5, 3, 1}},
+		{3, 3, 1, []int{3}},
+		{9223372036854775806, 9223372036854775807, 1, []int{9223372036854775806, 9223372036854775807}},
+		{-9223372036854775807, -9223372036854775808, -1, []int{-9223372036854775807, -9223372036854775808}},
+		{-9223372036854775808, 9223372036854775807, 9223372036854775807, []int{-9223372036854775808, -1, 9223372036854775806}},
+	}
+	for _, tt := range tests {
+		db := NewDatabase("")
+		if err := db.Range("r", tt.start, tt.end, tt.step); err != nil {
+			t.Errorf("Range(%d, %d, %d): %v", tt.start, tt.end, tt.step, err)
+			continue
+		}
+		got, _ := db.Get("r")
+		if !equalInts(got, tt.want) {
+			t.Errorf("Range(%d, %d, %d) = %v, want %v", tt.start, tt.end, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestRangeErrors(t *testing.T) {
+	tests := []struct{ start, end, step int }{
+		{1, 5, 0},
+		{1, 5, -1},
+		{5, 1, 1},
+		{0, maxArrayLen, 1},
+		{-9223372036854775808, 9223372036854775807, 1},
+	}
+	for _, tt := range tests {
+		db := NewDatabase("")
+		if err := db.Range("r", tt.start, tt.end, tt.step); err == nil {
+			t.Errorf("Range(%d, %d, %d) succeeded, want error", tt.start, tt.end, tt.step)
+		}
+	}
+}
+
+func BenchmarkConcurrentReads(b *testing.B) {
+	db := NewDatabase("")
+	value := make([]int, 1000)
+	for i := range value {
+		value[i] = i
+	}
+	db.Set("a", value)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := db.Get("a"); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := db.Len("a"); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := db.Sum("a"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	db := NewDatabase(path)
+	db.compress = true
+	db.Set("a", []int{1, 2, 3})
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload without compression enabled and save again: the file must
+	// come back compressed
+	reloaded := NewDatabase(path)
+	if err := reloaded.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	value, err := reloaded.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(value, []int{1, 2, 3}) {
+		t.Errorf("a = %v, want [1 2 3]", value)
+	}
+	if err := reloaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerLen := len(formatMagic) + 8
+	if len(raw) < headerLen+2 || raw[headerLen] != 0x1f || raw[headerLen+1] != 0x8b {
+		t.Error("database was saved uncompressed after reloading a compressed file")
+	}
+}
+
+// encodeTestData returns a database file holding a single array
+func encodeTestData(t *testing.T) []byte {
+	db := NewDatabase("")
+	db.Set("a", []int{1, 2, 3})
+	var buf bytes.Buffer
+	if err := db.encodeData(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeChecksummedFile(t *testing.T) {
+	data, _, err := decodeData(bytes.NewReader(encodeTestData(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(data["a"], []int{1, 2, 3}) {
+		t.Errorf("a = %v, want [1 2 3]", data["a"])
+	}
+}
+
+func TestDecodeCorruptedPayload(t *testing.T) {
+	raw := encodeTestData(t)
+	raw[len(raw)-1] ^= 0xff
+
+	_, _, err := decodeData(bytes.NewReader(raw))
+	if err == nil || !strings.Contains(err.Error(), "database file is corrupted") {
+		t.Errorf("err = %v, want database file is corrupted", err)
+	}
+}
+
+func TestDecodeTruncatedHeader(t *testing.T) {
+	raw := encodeTestData(t)[:len(formatMagic)+6]
+
+	_, _, err := decodeData(bytes.NewReader(raw))
+	if err == nil || !strings.Contains(err.Error(), "database file is corrupted") {
+		t.Errorf("err = %v, want database file is corrupted", err)
+	}
+}
+
+func TestDecodeLegacyHeaderlessFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(map[string][]int{"a": {4, 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _, err := decodeData(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(data["a"], []int{4, 5}) {
+		t.Errorf("a = %v, want [4 5]", data["a"])
+	}
+}
+
+func TestRemoteSessionRejectsLocalCommands(t *testing.T) {
+	db := NewDatabase("")
+	db.Set("a", []int{1})
+	path := filepath.Join(t.TempDir(), "out.json")
+
+	var out bytes.Buffer
+	sess := newSession(db, &out)
+	sess.remote = true
+	sess.dispatch([]string{"export", path})
+	sess.dispatch([]string{"undo"})
+	sess.dispatch([]string{"reload!"})
+
+	if _, err := os.Stat(path); err == nil {
+		t.Error("export wrote a file from a remote session")
+	}
+	want := "Error: export is not available over TCP\nError: undo is not available over TCP\n" +
+		"Error: reload! is not available over TCP\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSourceMarksSessionDirty(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "setup.wkn")
+	if err := os.WriteFile(script, []byte("new q 5,6\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	sess := newSession(NewDatabase(""), &out)
+	sess.dispatch([]string{"len", "q"})
+	if sess.dirty {
+		t.Error("session dirty after a read-only command")
+	}
+	sess.dispatch([]string{"source", script})
+	if !sess.dirty {
+		t.Error("session not dirty after sourcing a mutating script")
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	db := NewDatabase("")
+	db.Set("a", []int{1})
+
+	n, err := db.ImportJSON(strings.NewReader(`{"b": [2, 3], "c": null}`+"\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("imported %d keys, want 2", n)
+	}
+	if value, _ := db.Get("b"); !equalInts(value, []int{2, 3}) {
+		t.Errorf("b = %v, want [2 3]", value)
+	}
+}
+
+func TestImportJSONRejectsMalformedInput(t *testing.T) {
+	for _, input := range []string{"null", `{"a": [1]} {"b": [2]}`, `{"a": [1]} x`, `[1, 2]`, `{"a": "x"}`} {
+		db := NewDatabase("")
+		db.Set("keep", []int{1})
+		if _, err := db.ImportJSON(strings.NewReader(input), true); err == nil {
+			t.Errorf("ImportJSON(%q) succeeded, want error", input)
+		}
+		if _, err := db.Get("keep"); err != nil {
+			t.Errorf("ImportJSON(%q) discarded existing data", input)
+		}
+	}
+}
+
+func TestNoUndoSessionSkipsSnapshots(t *testing.T) {
+	var out bytes.Buffer
+	sess := newSession(NewDatabase(""), &out)
+	sess.noUndo = true
+	sess.dispatch([]string{"new", "a", "1,2"})
+	if len(sess.undoStack) != 0 {
+		t.Errorf("undo stack has %d snapshots, want 0", len(sess.undoStack))
+	}
+
+	out.Reset()
+	sess.dispatch([]string{"undo"})
+	if !strings.HasPrefix(out.String(), "Error:") {
+		t.Errorf("undo output = %q, want an error", out.String())
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{100000.5, "100000.5"},
+		{2, "2"},
+		{1.0 / 3, "0.3333"},
+		{-2.25, "-2.25"},
+		{-0.00001, "0"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.v); got != tt.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeUnsupportedVersion(t *testing.T) {
+	raw := encodeTestData(t)
+	raw[len(formatMagic)+3]++
+
+	_, _, err := decodeData(bytes.NewReader(raw))
+	if err == nil || !strings.Contains(err.Error(), "unsupported database version") {
+		t.Errorf("err = %v, want unsupported database version", err)
+	}
+}
+
+func TestSessionFormat(t *testing.T) {
+	db := NewDatabase("")
+	db.Set("a", []int{1, 2})
+
+	var out bytes.Buffer
+	sess := newSession(db, &out)
+	sess.dispatch([]string{"show", "a"})
+	sess.format = "json"
+	sess.dispatch([]string{"show", "a"})
+
+	want := "[1 2]\n[1,2]\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Server exposes the database over a JSON REST API
+type Server struct {
+	db *Database
+}
+
+// NewServer creates a server backed by the given database
+func NewServer(db *Database) *Server {
+	return &Server{db: db}
+}
+
+// ServeHTTP routes requests under /arrays to the matching database operation
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "arrays" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if readOnly && r.Method != http.MethodGet {
+		http.Error(w, "database is read-only", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.handleArrays(w, r)
+	case len(parts) == 2 && parts[1] != "":
+		s.handleArray(w, r, parts[1])
+	case len(parts) == 3 && parts[1] != "" && parts[2] == "merge":
+		s.handleMerge(w, r, parts[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleArrays serves GET /arrays
+func (s *Server) handleArrays(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, s.db.Keys())
+}
+
+// handleArray serves GET, PUT and DELETE on /arrays/{key}
+func (s *Server) handleArray(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		value, err := s.db.Get(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, value)
+	case http.MethodPut:
+		var value []int
+		if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+			http.Error(w, "invalid JSON array: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if value == nil {
+			value = []int{}
+		}
+		s.db.Set(key, value)
+		s.save(w)
+	case http.MethodDelete:
+		if err := s.db.Delete(key); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.save(w)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// handleMerge serves POST /arrays/{key}/merge?src=other
+func (s *Server) handleMerge(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	src := r.URL.Query().Get("src")
+	if src == "" {
+		http.Error(w, "missing src parameter", http.StatusBadRequest)
+		return
+	}
+	if err := s.db.Merge(key, src); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.save(w)
+}
+
+// save persists the database after a successful write and reports the
+// outcome to the client
+func (s *Server) save(w http.ResponseWriter) {
+	if err := s.db.Save(); err != nil {
+		http.Error(w, "error saving database: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
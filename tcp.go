@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// TCPServer serves the REPL command language over a line-oriented TCP
+// protocol. Each connection gets its own session and may send the same
+// commands as the REPL, one per line, except undo, reload! and the
+// commands that read or write files.
+type TCPServer struct {
+	db       *Database
+	listener net.Listener
+
+	// format is the output format of show for every connection
+	format string
+
+	mutex  sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// ListenTCP creates a TCP server for db listening on addr
+func ListenTCP(addr string, db *Database) (*TCPServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TCPServer{
+		db:       db,
+		listener: listener,
+		format:   "plain",
+		conns:    make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Addr returns the address the server is listening on
+func (s *TCPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve accepts connections until the server is closed
+func (s *TCPServer) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mutex.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// their handlers to finish
+func (s *TCPServer) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	err := s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// handle runs the commands sent on a single connection
+func (s *TCPServer) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	// A panic in one command must not take down the other connections,
+	// so report it to this client and drop the connection
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintln(conn, "Error: internal error:", r)
+			fmt.Println("Error in connection from", conn.RemoteAddr().String()+":", r)
+		}
+	}()
+
+	sess := newSession(s.db, conn)
+	sess.remote = true
+	sess.noUndo = true
+	sess.format = s.format
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		parts := splitCommand(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		if !sess.dispatch(parts) {
+			return
+		}
+	}
+}